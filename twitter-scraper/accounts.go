@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"x-cookies/internal/accounts"
+)
+
+// resolveAccountsDBPath 解析账号数据库路径，优先级：环境变量 > cookies 目录下的默认文件
+func resolveAccountsDBPath() string {
+	if env := os.Getenv("ACCOUNTS_DB_PATH"); env != "" {
+		return env
+	}
+	return filepath.Join(resolveOutputDir(), "accounts.db")
+}
+
+// resolveAccountFlag 解析本次运行要使用的账号昵称，优先级：命令行参数 > X_ACCOUNT 环境变量
+func resolveAccountFlag(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return resolveAccountEnv()
+}
+
+// resolveAllAccounts 解析是否对所有健康账号执行同一查询
+func resolveAllAccounts(flagValue bool) bool {
+	if flagValue {
+		return true
+	}
+	return strings.ToLower(os.Getenv("ACCOUNTS_ALL")) == "true"
+}
+
+// resolveVerifyCron 解析后台 cookie 校验的 cron 间隔，默认每 30 分钟一次
+func resolveVerifyCron(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv("ACCOUNTS_VERIFY_CRON"); env != "" {
+		return env
+	}
+	return "@every 30m"
+}
+
+// importLegacyCookieFile 将旧版单文件 cookie 导入账号数据库，作为历史数据的迁移路径
+func importLegacyCookieFile(store *accounts.Store, nick, cookieFile string) error {
+	data, err := os.ReadFile(cookieFile)
+	if err != nil {
+		return fmt.Errorf("failed to read legacy cookie file: %w", err)
+	}
+
+	var cookies []*http.Cookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return fmt.Errorf("failed to unmarshal legacy cookies: %w", err)
+	}
+
+	return store.Upsert(accounts.Account{
+		Nick:        nick,
+		CookiesJSON: string(data),
+		LoginTime:   time.Now(),
+		Status:      accounts.StatusHealthy,
+	})
+}
+
+// scraperForAccount 基于账号表中存储的 cookies 构造一个可用的 Scraper
+func scraperForAccount(acc accounts.Account, proxyAddr string) (*Scraper, error) {
+	var cookies []*http.Cookie
+	if err := json.Unmarshal([]byte(acc.CookiesJSON), &cookies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cookies for account %q: %w", acc.Nick, err)
+	}
+
+	scraper := NewScraper()
+	scraper.SetSkipLoginVerification(true)
+	if proxyAddr != "" {
+		if err := scraper.SetProxy(proxyAddr); err != nil {
+			return nil, fmt.Errorf("failed to set proxy for account %q: %w", acc.Nick, err)
+		}
+	}
+	scraper.SetCookies(cookies)
+	return scraper, nil
+}
+
+// verifyAccount 是 accounts.VerifyFunc 的具体实现，复用 verifyCookiesDirectly。
+// 当校验过程中刷新了 ct0，刷新后的 cookies 会写回账号数据库；onRefresh（可为 nil）
+// 会在此之后被调用，供调用方把刷新结果同步进其他持有这份 cookies 的地方
+// （例如 daemon 模式下常驻的 scraperPool）
+func verifyAccount(store *accounts.Store, proxyAddr string, onRefresh func(nick string, cookies []*http.Cookie)) accounts.VerifyFunc {
+	return func(acc accounts.Account) error {
+		var cookies []*http.Cookie
+		if err := json.Unmarshal([]byte(acc.CookiesJSON), &cookies); err != nil {
+			return fmt.Errorf("failed to unmarshal cookies for account %q: %w", acc.Nick, err)
+		}
+
+		refreshed, err := verifyCookiesDirectly(cookies, proxyAddr)
+		if err != nil {
+			return err
+		}
+
+		if !cookiesEqual(cookies, refreshed) {
+			data, err := json.Marshal(refreshed)
+			if err == nil {
+				acc.CookiesJSON = string(data)
+				if err := store.Upsert(acc); err != nil {
+					log.Printf("Failed to persist refreshed ct0 for account %q: %v", acc.Nick, err)
+				}
+			}
+			if onRefresh != nil {
+				onRefresh(acc.Nick, refreshed)
+			}
+		}
+		return nil
+	}
+}
+
+// runAccountsMode 打开账号数据库，启动后台校验调度，并对选中的账号执行查询，
+// 将每个账号抓到的推文逐条写入 sink（与单账号路径使用同一套 -output/-output-target 配置）
+func runAccountsMode(account string, all bool, query string, count int, proxyAddr, verifyCronSpec string, sink OutputSink) error {
+	dbPath := resolveAccountsDBPath()
+	log.Printf("Opening accounts database: %s", dbPath)
+
+	store, err := accounts.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	verify := verifyAccount(store, proxyAddr, nil)
+
+	// 启动时立即同步校验一遍所有账号，避免查询用到陈旧的健康状态；
+	// cron 只负责后续运行期间的周期性复查
+	log.Println("Verifying all accounts before selecting targets")
+	expired, err := store.VerifyAll(verify)
+	if err != nil {
+		return fmt.Errorf("failed to verify accounts on startup: %w", err)
+	}
+	for _, nick := range expired {
+		log.Printf("Account %q marked unhealthy (cookies expired)", nick)
+	}
+
+	scheduler, err := accounts.NewScheduler(store, verifyCronSpec, verify)
+	if err != nil {
+		return fmt.Errorf("failed to start verification scheduler: %w", err)
+	}
+	scheduler.SetOnCookieExpired(func(nick string) {
+		log.Printf("Account %q needs re-login (cookies expired)", nick)
+	})
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	var targets []accounts.Account
+	if all {
+		targets, err = store.Healthy()
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("no healthy accounts found in %s", dbPath)
+		}
+	} else {
+		acc, err := store.Get(account)
+		if err != nil {
+			return err
+		}
+		if acc.Status != accounts.StatusHealthy {
+			return fmt.Errorf("account %q is marked unhealthy, re-login required", account)
+		}
+		targets = []accounts.Account{acc}
+	}
+
+	return runQueryForAccounts(targets, query, count, proxyAddr, sink)
+}
+
+// runQueryForAccounts 对给定账号列表依次执行同一搜索查询，每个账号抓到的推文
+// 在抓取循环内直接写入 sink，不在内存里攒成切片
+func runQueryForAccounts(accts []accounts.Account, query string, count int, proxyAddr string, sink OutputSink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for _, acc := range accts {
+		scraper, err := scraperForAccount(acc, proxyAddr)
+		if err != nil {
+			log.Printf("Skipping account %q: %v", acc.Nick, err)
+			continue
+		}
+
+		log.Printf("Querying account @%s", acc.Nick)
+		if err := searchTweetsToSink(ctx, scraper, query, count, sink, proxyAddr); err != nil {
+			log.Printf("Search failed for account %q: %v", acc.Nick, err)
+			continue
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoginWithCredentials 使用用户名/密码（可选邮箱确认或 2FA/TOTP 验证码）登录，
+// 作为没有可用 cookie 文件时的备用认证方式
+func (s *Scraper) LoginWithCredentials(username, password, confirmation string) error {
+	if username == "" || password == "" {
+		return fmt.Errorf("username and password are required for credential login")
+	}
+
+	log.Printf("Logging in as @%s using username/password", username)
+
+	var err error
+	if confirmation != "" {
+		err = s.Scraper.Login(username, password, confirmation)
+	} else {
+		err = s.Scraper.Login(username, password)
+	}
+	if err != nil {
+		return fmt.Errorf("credential login failed: %w", err)
+	}
+
+	log.Println("Credential login succeeded")
+	return nil
+}
+
+// LoginOpenAccount 获取一个 Twitter "open account"（访客 app）会话，
+// 用于在没有真实用户的情况下进行只读抓取
+func (s *Scraper) LoginOpenAccount() error {
+	log.Println("Acquiring open account (guest) session")
+
+	if _, err := s.Scraper.LoginOpenAccount(); err != nil {
+		return fmt.Errorf("open account login failed: %w", err)
+	}
+
+	log.Println("Open account session acquired")
+	return nil
+}
+
+// persistCookies 将 scraper 当前的 cookie jar 写回指定文件，
+// 以便后续运行可以复用新获取的会话
+func persistCookies(scraper *Scraper, cookieFile string) error {
+	if cookieFile == "" {
+		return fmt.Errorf("cookie file path is empty, nothing to persist")
+	}
+
+	cookies := scraper.GetCookies()
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cookies: %w", err)
+	}
+
+	if dir := filepath.Dir(cookieFile); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create cookie directory: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(cookieFile, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write cookie file: %w", err)
+	}
+
+	log.Printf("Persisted %d cookies to %s", len(cookies), cookieFile)
+	return nil
+}
+
+// resolveUsername 解析用户名，优先级：命令行参数 > 环境变量
+func resolveUsername(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv("TWITTER_USERNAME")
+}
+
+// resolvePassword 解析密码，优先级：命令行参数 > 环境变量
+func resolvePassword(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv("TWITTER_PASSWORD")
+}
+
+// resolveConfirmation 解析邮箱确认码或 2FA/TOTP 验证码，优先级：命令行参数 > 环境变量
+func resolveConfirmation(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv("TWITTER_2FA_CODE")
+}
+
+// resolveOpenAccount 解析是否启用 open-account 模式，优先级：命令行参数 > 环境变量
+func resolveOpenAccount(flagValue bool) bool {
+	if flagValue {
+		return true
+	}
+	return strings.ToLower(os.Getenv("TWITTER_OPEN_ACCOUNT")) == "true"
+}
+
+// loginMode 枚举 establishSession 在 cookie 文件不可用（或未提供）之后
+// 应该尝试的登录方式
+type loginMode int
+
+const (
+	loginModeCredentials loginMode = iota
+	loginModeOpenAccount
+	loginModeNone
+)
+
+// resolveLoginMode 根据是否提供了用户名、是否显式要求 open-account、
+// 以及 cookie 校验是否失败，决定接下来尝试哪种登录方式。
+// cookie 校验失败且没有提供用户名时，即使没有显式传 -open-account，
+// 也要自动回退到 open-account，而不是直接报错退出
+func resolveLoginMode(username string, openAccount, cookieVerificationFailed bool) loginMode {
+	switch {
+	case username != "":
+		return loginModeCredentials
+	case openAccount || cookieVerificationFailed:
+		return loginModeOpenAccount
+	default:
+		return loginModeNone
+	}
+}
+
+// establishSession 依次尝试 cookie 文件、用户名/密码、open-account 三种登录方式，
+// 并在 cookie 校验失败时自动回退到 open-account 模式
+func establishSession(scraper *Scraper, cookieFile, username, password, confirmation string, openAccount bool, proxyAddr string) error {
+	cookieVerificationFailed := false
+	if cookieFile != "" {
+		if _, err := os.Stat(cookieFile); err == nil {
+			cookies, err := loadCookiesFromFile(scraper, cookieFile)
+			if err == nil {
+				refreshed, err := verifyCookiesDirectly(cookies, proxyAddr)
+				if err == nil {
+					if !cookiesEqual(cookies, refreshed) {
+						scraper.SetCookies(refreshed)
+						if err := persistCookies(scraper, cookieFile); err != nil {
+							log.Printf("Failed to persist refreshed ct0 (continuing): %v", err)
+						}
+					}
+					return nil
+				}
+				log.Printf("Cookie verification failed, falling back to another login mode")
+				cookieVerificationFailed = true
+			} else {
+				log.Printf("Failed to load cookies from file (continuing): %v", err)
+			}
+		}
+	}
+
+	switch resolveLoginMode(username, openAccount, cookieVerificationFailed) {
+	case loginModeCredentials:
+		if err := scraper.LoginWithCredentials(username, password, confirmation); err != nil {
+			if !openAccount && !cookieVerificationFailed {
+				return err
+			}
+			log.Printf("Credential login failed, falling back to open account: %v", err)
+			if err := scraper.LoginOpenAccount(); err != nil {
+				return err
+			}
+		}
+	case loginModeOpenAccount:
+		if err := scraper.LoginOpenAccount(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("no usable login mode: provide -cookies, -username/-password, or -open-account")
+	}
+
+	if cookieFile != "" {
+		if err := persistCookies(scraper, cookieFile); err != nil {
+			log.Printf("Failed to persist refreshed cookies (continuing): %v", err)
+		}
+	}
+	return nil
+}
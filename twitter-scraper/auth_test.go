@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestResolveLoginMode(t *testing.T) {
+	tests := []struct {
+		name                     string
+		username                 string
+		openAccount              bool
+		cookieVerificationFailed bool
+		want                     loginMode
+	}{
+		{
+			name:                     "username provided uses credentials regardless of other flags",
+			username:                 "alice",
+			openAccount:              true,
+			cookieVerificationFailed: true,
+			want:                     loginModeCredentials,
+		},
+		{
+			name:        "no username but open-account flag set",
+			openAccount: true,
+			want:        loginModeOpenAccount,
+		},
+		{
+			name:                     "no username, no open-account flag, but cookie verification failed",
+			cookieVerificationFailed: true,
+			want:                     loginModeOpenAccount,
+		},
+		{
+			name: "no username, no open-account flag, no failed cookie verification",
+			want: loginModeNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveLoginMode(tt.username, tt.openAccount, tt.cookieVerificationFailed)
+			if got != tt.want {
+				t.Errorf("resolveLoginMode(%q, %v, %v) = %v, want %v", tt.username, tt.openAccount, tt.cookieVerificationFailed, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const twitterHomeURL = "https://twitter.com/"
+
+// isStaleCSRFResponse 判断一个响应是否意味着 ct0 已经过期，需要刷新
+func isStaleCSRFResponse(resp *http.Response, body []byte) bool {
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "csrf") || strings.Contains(lower, "ct0")
+}
+
+// RefreshCSRF 访问 twitter.com 首页以触发一个新的 ct0，并把响应中的 Set-Cookie
+// 合并进传入的 cookies，供 verifyCookiesDirectly 和底层 twitterscraper 传输层共用
+func RefreshCSRF(client *http.Client, cookies []*http.Cookie) ([]*http.Cookie, error) {
+	homeURL, err := url.Parse(twitterHomeURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse twitter home url: %w", err)
+	}
+	if client.Jar != nil {
+		client.Jar.SetCookies(homeURL, cookies)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, twitterHomeURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build csrf refresh request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("csrf refresh request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	fresh := resp.Cookies()
+	if client.Jar != nil {
+		fresh = append(fresh, client.Jar.Cookies(homeURL)...)
+	}
+
+	return mergeCookies(cookies, fresh), nil
+}
+
+// isStaleCSRFError 判断 twitterscraper 请求返回的 error 是否意味着 ct0 已经过期。
+// twitterscraper 把非 200 响应包装成 "response status <status>: <body>" 的 error，
+// 因此这里按 isStaleCSRFResponse 同样的规则在文本上做判断
+func isStaleCSRFError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	if !strings.Contains(msg, "403") {
+		return false
+	}
+	return strings.Contains(msg, "csrf") || strings.Contains(msg, "ct0")
+}
+
+// cookiesEqual 判断两组 cookie 在名称和取值上是否完全一致，用于决定是否需要重新持久化
+func cookiesEqual(a, b []*http.Cookie) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	values := make(map[string]string, len(a))
+	for _, c := range a {
+		values[c.Name] = c.Value
+	}
+	for _, c := range b {
+		if v, ok := values[c.Name]; !ok || v != c.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeCookies 用 fresh 中同名的 cookie 覆盖 existing，未出现在 fresh 中的保留不变
+func mergeCookies(existing, fresh []*http.Cookie) []*http.Cookie {
+	byName := make(map[string]*http.Cookie, len(existing)+len(fresh))
+	for _, c := range existing {
+		byName[c.Name] = c
+	}
+	for _, c := range fresh {
+		byName[c.Name] = c
+	}
+
+	merged := make([]*http.Cookie, 0, len(byName))
+	for _, c := range byName {
+		merged = append(merged, c)
+	}
+	return merged
+}
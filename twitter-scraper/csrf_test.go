@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMergeCookies(t *testing.T) {
+	existing := []*http.Cookie{
+		{Name: "auth_token", Value: "old-auth"},
+		{Name: "ct0", Value: "old-ct0"},
+	}
+	fresh := []*http.Cookie{
+		{Name: "ct0", Value: "new-ct0"},
+	}
+
+	merged := mergeCookies(existing, fresh)
+
+	values := make(map[string]string, len(merged))
+	for _, c := range merged {
+		values[c.Name] = c.Value
+	}
+
+	if values["ct0"] != "new-ct0" {
+		t.Errorf("expected ct0 to be refreshed to %q, got %q", "new-ct0", values["ct0"])
+	}
+	if values["auth_token"] != "old-auth" {
+		t.Errorf("expected auth_token to be preserved, got %q", values["auth_token"])
+	}
+	if len(merged) != 2 {
+		t.Errorf("expected 2 merged cookies, got %d", len(merged))
+	}
+}
+
+func TestCookiesEqual(t *testing.T) {
+	a := []*http.Cookie{{Name: "ct0", Value: "abc"}, {Name: "auth_token", Value: "xyz"}}
+
+	same := []*http.Cookie{{Name: "auth_token", Value: "xyz"}, {Name: "ct0", Value: "abc"}}
+	if !cookiesEqual(a, same) {
+		t.Error("expected cookiesEqual to ignore ordering and report equal")
+	}
+
+	differentValue := []*http.Cookie{{Name: "ct0", Value: "changed"}, {Name: "auth_token", Value: "xyz"}}
+	if cookiesEqual(a, differentValue) {
+		t.Error("expected cookiesEqual to detect a changed value")
+	}
+
+	differentLength := []*http.Cookie{{Name: "ct0", Value: "abc"}}
+	if cookiesEqual(a, differentLength) {
+		t.Error("expected cookiesEqual to detect a different cookie count")
+	}
+}
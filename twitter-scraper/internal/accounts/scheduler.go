@@ -0,0 +1,70 @@
+package accounts
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler 周期性地重新校验账号表中的 cookie，并在发现失效账号时触发回调
+type Scheduler struct {
+	store  *Store
+	verify VerifyFunc
+	cron   *cron.Cron
+
+	mu        sync.Mutex
+	onExpired func(account string)
+}
+
+// NewScheduler 创建一个按 cronSpec（标准 5 字段 cron 表达式，或 "@every 30m" 这类描述符）运行的校验调度器
+func NewScheduler(store *Store, cronSpec string, verify VerifyFunc) (*Scheduler, error) {
+	sch := &Scheduler{
+		store:  store,
+		verify: verify,
+		cron:   cron.New(),
+	}
+
+	if _, err := sch.cron.AddFunc(cronSpec, sch.runOnce); err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: %w", cronSpec, err)
+	}
+
+	return sch, nil
+}
+
+// SetOnCookieExpired 注册一个回调，每当某个账号被标记为失效时调用，便于通知运维人员重新登录
+func (sch *Scheduler) SetOnCookieExpired(fn func(account string)) {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+	sch.onExpired = fn
+}
+
+// Start 启动后台 cron 调度
+func (sch *Scheduler) Start() {
+	sch.cron.Start()
+}
+
+// Stop 停止调度并等待正在运行的任务结束
+func (sch *Scheduler) Stop() {
+	<-sch.cron.Stop().Done()
+}
+
+func (sch *Scheduler) runOnce() {
+	expired, err := sch.store.VerifyAll(sch.verify)
+	if err != nil {
+		log.Printf("accounts: scheduled verification failed: %v", err)
+		return
+	}
+
+	sch.mu.Lock()
+	onExpired := sch.onExpired
+	sch.mu.Unlock()
+
+	for _, nick := range expired {
+		log.Printf("accounts: cookie expired for account %q", nick)
+		if onExpired != nil {
+			onExpired(nick)
+		}
+	}
+}
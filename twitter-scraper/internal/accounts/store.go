@@ -0,0 +1,179 @@
+// Package accounts 提供一个 SQLite 持久化的多账号 cookie 管理器，
+// 用于替代单文件的 resolveCookieFile 流程
+package accounts
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Status 取值
+const (
+	StatusExpired = 0
+	StatusHealthy = 1
+)
+
+// Account 对应 accounts 表中的一条记录
+type Account struct {
+	Nick        string
+	UID         string
+	CookiesJSON string
+	LoginTime   time.Time
+	Status      int
+	PushID      string
+}
+
+// Store 是账号表的并发安全访问入口
+type Store struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// Open 打开（或创建）指定路径的账号数据库
+func Open(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open accounts db: %w", err)
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS accounts (
+	nick TEXT PRIMARY KEY,
+	uid TEXT,
+	cookies_json TEXT NOT NULL,
+	login_time DATETIME NOT NULL,
+	status INTEGER NOT NULL DEFAULT 1,
+	push_id TEXT
+);`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create accounts table: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close 关闭底层数据库连接
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// List 返回所有已知账号，包括状态为过期的账号
+func (s *Store) List() ([]Account, error) {
+	rows, err := s.db.Query(`SELECT nick, uid, cookies_json, login_time, status, push_id FROM accounts ORDER BY nick`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Account
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.Nick, &a.UID, &a.CookiesJSON, &a.LoginTime, &a.Status, &a.PushID); err != nil {
+			return nil, fmt.Errorf("failed to scan account row: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// Healthy 返回所有 status=StatusHealthy 的账号
+func (s *Store) Healthy() ([]Account, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var healthy []Account
+	for _, a := range all {
+		if a.Status == StatusHealthy {
+			healthy = append(healthy, a)
+		}
+	}
+	return healthy, nil
+}
+
+// Get 按昵称查找单个账号
+func (s *Store) Get(nick string) (Account, error) {
+	var a Account
+	row := s.db.QueryRow(`SELECT nick, uid, cookies_json, login_time, status, push_id FROM accounts WHERE nick = ?`, nick)
+	if err := row.Scan(&a.Nick, &a.UID, &a.CookiesJSON, &a.LoginTime, &a.Status, &a.PushID); err != nil {
+		return Account{}, fmt.Errorf("account %q not found: %w", nick, err)
+	}
+	return a, nil
+}
+
+// Upsert 插入或更新一个账号记录，通常在登录成功后调用
+func (s *Store) Upsert(a Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+INSERT INTO accounts (nick, uid, cookies_json, login_time, status, push_id)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(nick) DO UPDATE SET
+	uid = excluded.uid,
+	cookies_json = excluded.cookies_json,
+	login_time = excluded.login_time,
+	status = excluded.status,
+	push_id = excluded.push_id`,
+		a.Nick, a.UID, a.CookiesJSON, a.LoginTime, a.Status, a.PushID)
+	if err != nil {
+		return fmt.Errorf("failed to upsert account %q: %w", a.Nick, err)
+	}
+	return nil
+}
+
+// SetStatus 更新账号的健康状态，cookie 校验失败时调用以标记 status=StatusExpired
+func (s *Store) SetStatus(nick string, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`UPDATE accounts SET status = ? WHERE nick = ?`, status, nick)
+	if err != nil {
+		return fmt.Errorf("failed to update status for account %q: %w", nick, err)
+	}
+	return nil
+}
+
+// VerifyFunc 校验单个账号的 cookie 是否仍然有效
+type VerifyFunc func(Account) error
+
+// VerifyAll 并行对所有账号调用 verify，将失败的账号标记为 StatusExpired，
+// 并返回这些失效账号的昵称列表
+func (s *Store) VerifyAll(verify VerifyFunc) ([]string, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		expired []string
+	)
+
+	for _, a := range all {
+		wg.Add(1)
+		go func(acc Account) {
+			defer wg.Done()
+			if err := verify(acc); err != nil {
+				if setErr := s.SetStatus(acc.Nick, StatusExpired); setErr != nil {
+					mu.Lock()
+					expired = append(expired, acc.Nick)
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				expired = append(expired, acc.Nick)
+				mu.Unlock()
+			}
+		}(a)
+	}
+	wg.Wait()
+
+	return expired, nil
+}
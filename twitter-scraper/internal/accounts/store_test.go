@@ -0,0 +1,68 @@
+package accounts
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open in-memory store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStoreVerifyAllMarksStaleAccountsExpired(t *testing.T) {
+	store := newTestStore(t)
+
+	for i := 0; i < 5; i++ {
+		acc := Account{
+			Nick:        fmt.Sprintf("acct-%d", i),
+			CookiesJSON: "[]",
+			LoginTime:   time.Now(),
+			Status:      StatusHealthy,
+		}
+		if err := store.Upsert(acc); err != nil {
+			t.Fatalf("failed to seed account %q: %v", acc.Nick, err)
+		}
+	}
+
+	// 偶数编号的账号校验失败，奇数编号的账号校验成功，
+	// 模拟并发校验时只有一部分账号过期的场景
+	verify := func(acc Account) error {
+		var n int
+		fmt.Sscanf(acc.Nick, "acct-%d", &n)
+		if n%2 == 0 {
+			return fmt.Errorf("cookie expired for %s", acc.Nick)
+		}
+		return nil
+	}
+
+	expired, err := store.VerifyAll(verify)
+	if err != nil {
+		t.Fatalf("VerifyAll returned error: %v", err)
+	}
+	if len(expired) != 3 {
+		t.Fatalf("expected 3 expired accounts, got %d: %v", len(expired), expired)
+	}
+
+	all, err := store.List()
+	if err != nil {
+		t.Fatalf("failed to list accounts: %v", err)
+	}
+	for _, acc := range all {
+		var n int
+		fmt.Sscanf(acc.Nick, "acct-%d", &n)
+		wantStatus := StatusHealthy
+		if n%2 == 0 {
+			wantStatus = StatusExpired
+		}
+		if acc.Status != wantStatus {
+			t.Errorf("account %q: expected status %d, got %d", acc.Nick, wantStatus, acc.Status)
+		}
+	}
+}
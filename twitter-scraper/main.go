@@ -20,6 +20,8 @@ import (
 
 	twitterscraper "github.com/imperatrona/twitter-scraper"
 	"golang.org/x/net/proxy"
+
+	"x-cookies/internal/accounts"
 )
 
 // Scraper 模拟 tee-worker 的 Scraper 结构
@@ -184,33 +186,22 @@ func buildDirectHTTPClient(proxyAddr string) (*http.Client, error) {
 	return nil, fmt.Errorf("unsupported proxy protocol: %s", proxyAddr)
 }
 
-// verifyCookiesDirectly 通过直接访问 verify_credentials 接口验证 cookie 是否有效
-func verifyCookiesDirectly(cookies []*http.Cookie, proxyAddr string) error {
-	client, err := buildDirectHTTPClient(proxyAddr)
-	if err != nil {
-		return fmt.Errorf("failed to build verification http client: %w", err)
-	}
-
-	verifyURL, err := url.Parse(verifyEndpoint)
-	if err != nil {
-		return fmt.Errorf("failed to parse verify endpoint: %w", err)
-	}
-	client.Jar.SetCookies(verifyURL, cookies)
-
-	var csrfToken string
+// findCookie 在 cookie 切片中按名称查找，找不到时返回空字符串
+func findCookie(cookies []*http.Cookie, name string) string {
 	for _, cookie := range cookies {
-		if cookie.Name == "ct0" {
-			csrfToken = cookie.Value
-			break
+		if cookie.Name == name {
+			return cookie.Value
 		}
 	}
-	if csrfToken == "" {
-		return fmt.Errorf("ct0 cookie missing for verification")
-	}
+	return ""
+}
 
+// buildVerifyRequest 构造一次 verify_credentials 请求，csrfToken 随每次尝试传入，
+// 以便在刷新 ct0 后可以原样重建请求进行重试
+func buildVerifyRequest(csrfToken string) (*http.Request, error) {
 	req, err := http.NewRequest(http.MethodGet, verifyEndpoint, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create verify request: %w", err)
+		return nil, fmt.Errorf("failed to create verify request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+verifyBearerToken)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36")
@@ -219,19 +210,97 @@ func verifyCookiesDirectly(cookies []*http.Cookie, proxyAddr string) error {
 	req.Header.Set("X-Twitter-Active-User", "yes")
 	req.Header.Set("Referer", "https://twitter.com/")
 	req.Header.Set("Accept", "application/json, text/plain, */*")
+	return req, nil
+}
 
-	resp, err := client.Do(req)
+// verifyCookiesDirectly 通过直接访问 verify_credentials 接口验证 cookie 是否有效。
+// 遇到 403 且疑似 ct0 过期时，会通过 RefreshCSRF 刷新一次 token 并重试，
+// 返回的 cookies 是实际生效的集合（可能已包含刷新后的 ct0），供调用方持久化
+func verifyCookiesDirectly(cookies []*http.Cookie, proxyAddr string) ([]*http.Cookie, error) {
+	client, err := buildDirectHTTPClient(proxyAddr)
+	if err != nil {
+		return cookies, fmt.Errorf("failed to build verification http client: %w", err)
+	}
+
+	verifyURL, err := url.Parse(verifyEndpoint)
+	if err != nil {
+		return cookies, fmt.Errorf("failed to parse verify endpoint: %w", err)
+	}
+	client.Jar.SetCookies(verifyURL, cookies)
+
+	csrfToken := findCookie(cookies, "ct0")
+	if csrfToken == "" {
+		return cookies, fmt.Errorf("ct0 cookie missing for verification")
+	}
+
+	req, err := buildVerifyRequest(csrfToken)
 	if err != nil {
-		return fmt.Errorf("verification request failed: %w", err)
+		return cookies, err
 	}
-	defer resp.Body.Close()
 
+	resp, err := client.Do(req)
+	if err != nil {
+		return cookies, fmt.Errorf("verification request failed: %w", err)
+	}
 	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("verification failed: status %s, body: %s", resp.Status, string(body))
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		log.Printf("Direct cookie verification succeeded: %s", string(body))
+		return cookies, nil
+	}
+
+	if !isStaleCSRFResponse(resp, body) {
+		return cookies, fmt.Errorf("verification failed: status %s, body: %s", resp.Status, string(body))
+	}
+
+	log.Println("Detected stale CSRF token, refreshing ct0 and retrying once")
+	refreshed, err := RefreshCSRF(client, cookies)
+	if err != nil {
+		return cookies, fmt.Errorf("verification failed: status %s, body: %s (csrf refresh also failed: %v)", resp.Status, string(body), err)
+	}
+
+	csrfToken = findCookie(refreshed, "ct0")
+	if csrfToken == "" {
+		return refreshed, fmt.Errorf("ct0 cookie still missing after csrf refresh")
 	}
 
-	log.Printf("Direct cookie verification succeeded: %s", string(body))
+	retryReq, err := buildVerifyRequest(csrfToken)
+	if err != nil {
+		return refreshed, err
+	}
+
+	retryResp, err := client.Do(retryReq)
+	if err != nil {
+		return refreshed, fmt.Errorf("verification retry request failed: %w", err)
+	}
+	defer retryResp.Body.Close()
+
+	retryBody, _ := io.ReadAll(retryResp.Body)
+	if retryResp.StatusCode != http.StatusOK {
+		return refreshed, fmt.Errorf("verification failed after csrf refresh: status %s, body: %s", retryResp.Status, string(retryBody))
+	}
+
+	log.Printf("Direct cookie verification succeeded after csrf refresh: %s", string(retryBody))
+	return refreshed, nil
+}
+
+// refreshScraperCSRF 用 scraper 当前的 cookies 触发一次 RefreshCSRF，并把刷新后的
+// cookies 写回 scraper 本身，供底层 twitterscraper 传输层在下一次请求中使用。
+// 这是 RefreshCSRF 在一次性预检（verifyCookiesDirectly）之外的第二个调用方，
+// 用来处理会话中途 ct0 过期的情况
+func refreshScraperCSRF(scraper *Scraper, proxyAddr string) error {
+	client, err := buildDirectHTTPClient(proxyAddr)
+	if err != nil {
+		return fmt.Errorf("failed to build csrf refresh http client: %w", err)
+	}
+
+	refreshed, err := RefreshCSRF(client, scraper.GetCookies())
+	if err != nil {
+		return err
+	}
+
+	scraper.SetCookies(refreshed)
 	return nil
 }
 
@@ -261,44 +330,89 @@ func convertTweet(tweet twitterscraper.Tweet) *TweetResult {
 	}
 }
 
-// searchTweets 执行搜索
-func searchTweets(scraper *Scraper, query string, count int) ([]*TweetResult, error) {
-	tweets := make([]*TweetResult, 0, count)
-
+// searchTweets 执行搜索；如果抓取过程中遇到 ct0 过期（stale CSRF），
+// 会刷新一次 scraper 的 cookies 后重新搜索
+func searchTweets(scraper *Scraper, query string, count int, proxyAddr string) ([]*TweetResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
 	scraper.SetSearchMode(twitterscraper.SearchLatest)
 
-	log.Printf("Searching for tweets with query: %s (max: %d)", query, count)
-	log.Printf("Login status: %v", scraper.IsLoggedIn())
+	for attempt := 0; attempt < 2; attempt++ {
+		tweets := make([]*TweetResult, 0, count)
+		staleCSRF := false
+
+		log.Printf("Searching for tweets with query: %s (max: %d)", query, count)
+		log.Printf("Login status: %v", scraper.IsLoggedIn())
+
+		for tweetScraped := range scraper.SearchTweets(ctx, query, count) {
+			if tweetScraped.Error != nil {
+				if attempt == 0 && isStaleCSRFError(tweetScraped.Error) {
+					staleCSRF = true
+					break
+				}
+				return nil, fmt.Errorf("error scraping tweet: %v", tweetScraped.Error)
+			}
 
-	for tweetScraped := range scraper.SearchTweets(ctx, query, count) {
-		if tweetScraped.Error != nil {
-			return nil, fmt.Errorf("error scraping tweet: %v", tweetScraped.Error)
+			tweetResult := convertTweet(tweetScraped.Tweet)
+			tweets = append(tweets, tweetResult)
+
+			log.Printf("Found tweet: @%s", tweetResult.Username)
 		}
 
-		tweetResult := convertTweet(tweetScraped.Tweet)
-		tweets = append(tweets, tweetResult)
+		if !staleCSRF {
+			return tweets, nil
+		}
 
-		log.Printf("Found tweet: @%s", tweetResult.Username)
+		log.Println("Detected stale CSRF token mid-session, refreshing ct0 and retrying search once")
+		if err := refreshScraperCSRF(scraper, proxyAddr); err != nil {
+			return nil, fmt.Errorf("failed to refresh ct0 after stale CSRF: %w", err)
+		}
 	}
 
-	return tweets, nil
+	return nil, nil
 }
 
-// printResults 输出结果
-func printResults(tweets []*TweetResult) {
-	fmt.Printf("\n=== 搜索结果 (%d 条推文) ===\n\n", len(tweets))
+// searchTweetsToSink 执行搜索，但在抓取循环内逐条写入 sink，
+// 避免长时间运行的搜索把全部结果都缓存在内存里；如果抓取过程中遇到 ct0 过期
+// （stale CSRF），会刷新一次 scraper 的 cookies 后重新搜索
+func searchTweetsToSink(ctx context.Context, scraper *Scraper, query string, count int, sink OutputSink, proxyAddr string) error {
+	scraper.SetSearchMode(twitterscraper.SearchLatest)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		staleCSRF := false
+
+		log.Printf("Searching for tweets with query: %s (max: %d)", query, count)
+		log.Printf("Login status: %v", scraper.IsLoggedIn())
+
+		for tweetScraped := range scraper.SearchTweets(ctx, query, count) {
+			if tweetScraped.Error != nil {
+				if attempt == 0 && isStaleCSRFError(tweetScraped.Error) {
+					staleCSRF = true
+					break
+				}
+				return fmt.Errorf("error scraping tweet: %v", tweetScraped.Error)
+			}
 
-	for i, tweet := range tweets {
-		fmt.Printf("--- 推文 %d ---\n", i+1)
-		fmt.Printf("用户: @%s\n", tweet.Username)
-		fmt.Printf("时间: %s\n", tweet.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("内容: %s\n", tweet.Text)
-		fmt.Printf("互动: ❤️ %d | 🔄 %d | 💬 %d\n", tweet.Likes, tweet.Retweets, tweet.Replies)
-		fmt.Println()
+			tweetResult := convertTweet(tweetScraped.Tweet)
+			if err := sink.Write(ctx, tweetResult); err != nil {
+				return fmt.Errorf("failed to write tweet to sink: %w", err)
+			}
+
+			log.Printf("Found tweet: @%s", tweetResult.Username)
+		}
+
+		if !staleCSRF {
+			return nil
+		}
+
+		log.Println("Detected stale CSRF token mid-session, refreshing ct0 and retrying search once")
+		if err := refreshScraperCSRF(scraper, proxyAddr); err != nil {
+			return fmt.Errorf("failed to refresh ct0 after stale CSRF: %w", err)
+		}
 	}
+
+	return nil
 }
 
 func resolveOutputDir() string {
@@ -382,11 +496,22 @@ func resolveProxy(flagValue string) string {
 
 func main() {
 	var (
-		cookieFile = flag.String("cookies", "", "Cookie 文件路径 (必需)")
-		query      = flag.String("query", "", "搜索查询 (必需)")
-		count      = flag.Int("count", 10, "最大结果数量")
-		jsonOutput = flag.Bool("json", false, "输出 JSON 格式")
-		proxy      = flag.String("proxy", "", "代理地址，支持 socks5，例如 socks5://127.0.0.1:1080")
+		cookieFile   = flag.String("cookies", "", "Cookie 文件路径")
+		query        = flag.String("query", "", "搜索查询 (必需)")
+		count        = flag.Int("count", 10, "最大结果数量")
+		jsonOutput   = flag.Bool("json", false, "输出 JSON 格式 (等价于 -output ndjson)")
+		proxy        = flag.String("proxy", "", "代理地址，支持 socks5，例如 socks5://127.0.0.1:1080")
+		username     = flag.String("username", "", "Twitter 用户名，cookies 缺失或过期时用于登录")
+		password     = flag.String("password", "", "Twitter 密码，配合 -username 使用")
+		twoFactor    = flag.String("2fa", "", "邮箱确认码或 2FA/TOTP 验证码")
+		openAccount  = flag.Bool("open-account", false, "使用 open account（访客 app）会话，无需真实用户登录")
+		account      = flag.String("account", "", "使用账号数据库中的指定账号 (数据库路径见 ACCOUNTS_DB_PATH 环境变量)")
+		allAccounts  = flag.Bool("all", false, "对账号数据库中所有健康账号执行同一查询")
+		verifyCron   = flag.String("verify-cron", "", "后台重新校验 cookie 的 cron 间隔，默认 @every 30m")
+		importFile   = flag.String("import-cookie-file", "", "将旧版单文件 cookie 导入账号数据库，需配合 -account 指定昵称")
+		serveAddr    = flag.String("serve", "", "以常驻 HTTP 服务模式运行，监听地址，例如 :8080")
+		output       = flag.String("output", "", "输出方式: ndjson|csv|webhook|redis|stdout (默认 stdout)")
+		outputTarget = flag.String("output-target", "", "webhook 的 URL，或 redis 的 redis://host:port?channel=<名称>")
 	)
 	flag.Parse()
 
@@ -394,13 +519,74 @@ func main() {
 	resolvedQuery := resolveQuery(*query)
 	resolvedCount := resolveCount(*count)
 	resolvedProxy := resolveProxy(*proxy)
+	resolvedUsername := resolveUsername(*username)
+	resolvedPassword := resolvePassword(*password)
+	resolvedConfirmation := resolveConfirmation(*twoFactor)
+	resolvedOpenAccount := resolveOpenAccount(*openAccount)
+	resolvedAccount := resolveAccountFlag(*account)
+	resolvedAllAccounts := resolveAllAccounts(*allAccounts)
+
+	// 常驻 HTTP 服务模式：持有一个账号 scraper 池，通过 /search、/stream、/healthz 对外提供服务
+	if *serveAddr != "" {
+		store, err := accounts.Open(resolveAccountsDBPath())
+		if err != nil {
+			log.Fatalf("Failed to open accounts database: %v", err)
+		}
+		defer store.Close()
+
+		if err := runDaemon(*serveAddr, store, resolvedProxy); err != nil {
+			log.Fatalf("Daemon stopped: %v", err)
+		}
+		return
+	}
 
-	if resolvedCookieFile == "" || resolvedQuery == "" {
-		fmt.Println("用法: go run main.go -cookies <cookie文件路径> -query <搜索查询> [-count <数量>] [-json] [-proxy <代理URL>]")
+	// 将旧版单文件 cookie 导入账号数据库，是一次性的迁移操作
+	if *importFile != "" {
+		store, err := accounts.Open(resolveAccountsDBPath())
+		if err != nil {
+			log.Fatalf("Failed to open accounts database: %v", err)
+		}
+		if err := importLegacyCookieFile(store, resolvedAccount, *importFile); err != nil {
+			store.Close()
+			log.Fatalf("Failed to import legacy cookie file: %v", err)
+		}
+		store.Close()
+		log.Printf("Imported %s into account %q", *importFile, resolvedAccount)
+		return
+	}
+
+	if resolvedQuery == "" {
+		fmt.Println("用法: go run main.go -query <搜索查询> [-cookies <cookie文件路径> | -username <用户名> -password <密码> [-2fa <验证码>] | -open-account | -account <账号名> | -all] [-count <数量>] [-json] [-proxy <代理URL>]")
+		os.Exit(1)
+	}
+
+	// 选择输出 sink，-json 为兼容旧用法，等价于 -output ndjson
+	outputKind := *output
+	if outputKind == "" && *jsonOutput {
+		outputKind = "ndjson"
+	}
+	sink, err := resolveOutputSink(outputKind, *outputTarget)
+	if err != nil {
+		log.Fatalf("Failed to create output sink: %v", err)
+	}
+	defer sink.Close()
+
+	// 多账号模式：从 SQLite 账号数据库中读取一个或全部健康账号执行同一查询
+	if resolvedAccount != "" || resolvedAllAccounts {
+		if err := runAccountsMode(resolvedAccount, resolvedAllAccounts, resolvedQuery, resolvedCount, resolvedProxy, resolveVerifyCron(*verifyCron), sink); err != nil {
+			log.Fatalf("Failed to run accounts mode: %v", err)
+		}
+		return
+	}
+
+	if resolvedCookieFile == "" && resolvedUsername == "" && !resolvedOpenAccount {
+		fmt.Println("用法: go run main.go -query <搜索查询> [-cookies <cookie文件路径> | -username <用户名> -password <密码> [-2fa <验证码>] | -open-account | -account <账号名> | -all] [-count <数量>] [-json] [-proxy <代理URL>]")
 		os.Exit(1)
 	}
 
-	log.Printf("使用 cookie 文件: %s", resolvedCookieFile)
+	if resolvedCookieFile != "" {
+		log.Printf("使用 cookie 文件: %s", resolvedCookieFile)
+	}
 	log.Printf("搜索查询: %s (max %d)", resolvedQuery, resolvedCount)
 	if resolvedProxy != "" {
 		log.Printf("代理: %s", resolvedProxy)
@@ -417,15 +603,10 @@ func main() {
 		}
 	}
 
-	// 加载 cookies
-	cookies, err := loadCookiesFromFile(scraper, resolvedCookieFile)
-	if err != nil {
-		log.Fatalf("Failed to load cookies: %v", err)
-	}
-
-	// 通过独立的 HTTP 调用再次校验 cookies 是否仍然有效
-	if err := verifyCookiesDirectly(cookies, resolvedProxy); err != nil {
-		log.Fatalf("Failed to verify cookies via direct request: %v", err)
+	// 依次尝试 cookie 文件、用户名/密码、open-account 登录，
+	// cookie 校验失败时自动回退到其他可用模式
+	if err := establishSession(scraper, resolvedCookieFile, resolvedUsername, resolvedPassword, resolvedConfirmation, resolvedOpenAccount, resolvedProxy); err != nil {
+		log.Fatalf("Failed to establish session: %v", err)
 	}
 
 	// 验证登录状态，确保搜索流程可以访问需要认证的接口
@@ -433,22 +614,12 @@ func main() {
 		log.Fatalf("Failed to verify login: %v", err)
 	}
 
-	// 执行搜索
-	tweets, err := searchTweets(scraper, resolvedQuery, resolvedCount)
-	if err != nil {
+	// 执行搜索，每条推文在抓取循环内直接写入 sink
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := searchTweetsToSink(ctx, scraper, resolvedQuery, resolvedCount, sink, resolvedProxy); err != nil {
 		log.Fatalf("Failed to search tweets: %v", err)
 	}
 
-	// 输出结果
-	if *jsonOutput {
-		jsonData, err := json.MarshalIndent(tweets, "", "  ")
-		if err != nil {
-			log.Fatalf("Failed to marshal JSON: %v", err)
-		}
-		fmt.Println(string(jsonData))
-	} else {
-		printResults(tweets)
-	}
-
-	log.Printf("Successfully scraped %d tweets", len(tweets))
+	log.Println("Search completed")
 }
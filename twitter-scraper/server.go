@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+
+	"x-cookies/internal/accounts"
+)
+
+// scraperPool 为每个账号持有一个常驻的 *Scraper，串行化访问以遵守 Twitter 的速率限制
+type scraperPool struct {
+	store     *accounts.Store
+	proxyAddr string
+
+	mu       sync.Mutex
+	scrapers map[string]*Scraper
+	locks    map[string]*sync.Mutex
+}
+
+func newScraperPool(store *accounts.Store, proxyAddr string) *scraperPool {
+	return &scraperPool{
+		store:     store,
+		proxyAddr: proxyAddr,
+		scrapers:  make(map[string]*Scraper),
+		locks:     make(map[string]*sync.Mutex),
+	}
+}
+
+// acquire 返回指定账号的 scraper 及其专属锁，必要时惰性创建
+func (p *scraperPool) acquire(account string) (*Scraper, *sync.Mutex, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if scraper, ok := p.scrapers[account]; ok {
+		return scraper, p.locks[account], nil
+	}
+
+	acc, err := p.store.Get(account)
+	if err != nil {
+		return nil, nil, err
+	}
+	if acc.Status != accounts.StatusHealthy {
+		return nil, nil, fmt.Errorf("account %q is marked unhealthy, re-login required", account)
+	}
+
+	scraper, err := scraperForAccount(acc, p.proxyAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.scrapers[account] = scraper
+	p.locks[account] = &sync.Mutex{}
+	return scraper, p.locks[account], nil
+}
+
+// updateCookies 把刷新后的 cookies 推送进指定账号已缓存的 scraper（如果存在），
+// 避免 verifyAccount 的 ct0 刷新只更新了数据库却留下一个仍然使用旧 token 的常驻 scraper
+func (p *scraperPool) updateCookies(account string, cookies []*http.Cookie) {
+	p.mu.Lock()
+	scraper, ok := p.scrapers[account]
+	lock := p.locks[account]
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	scraper.SetCookies(cookies)
+}
+
+type searchRequest struct {
+	Query   string `json:"query"`
+	Count   int    `json:"count"`
+	Account string `json:"account"`
+}
+
+// runDaemon 以常驻 HTTP 服务的形式提供 /search、/stream 和 /healthz 接口
+func runDaemon(addr string, store *accounts.Store, proxyAddr string) error {
+	pool := newScraperPool(store, proxyAddr)
+	verify := verifyAccount(store, proxyAddr, pool.updateCookies)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		handleSearch(w, r, pool)
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		handleStream(w, r, pool)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		handleHealthz(w, r, store, verify)
+	})
+
+	log.Printf("Serving on %s (endpoints: POST /search, GET /stream, GET /healthz)", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+func handleSearch(w http.ResponseWriter, r *http.Request, pool *scraperPool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req searchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+	if req.Count <= 0 {
+		req.Count = 10
+	}
+
+	scraper, lock, err := pool.acquire(req.Account)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	lock.Lock()
+	tweets, err := searchTweets(scraper, req.Query, req.Count, pool.proxyAddr)
+	lock.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tweets)
+}
+
+func handleStream(w http.ResponseWriter, r *http.Request, pool *scraperPool) {
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+	account := r.URL.Query().Get("account")
+	count := 10
+	if v := r.URL.Query().Get("count"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			count = parsed
+		}
+	}
+
+	scraper, lock, err := pool.acquire(account)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	scraper.SetSearchMode(twitterscraper.SearchLatest)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		staleCSRF := false
+
+		for tweetScraped := range scraper.SearchTweets(ctx, query, count) {
+			if tweetScraped.Error != nil {
+				if attempt == 0 && isStaleCSRFError(tweetScraped.Error) {
+					staleCSRF = true
+					break
+				}
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", tweetScraped.Error.Error())
+				flusher.Flush()
+				return
+			}
+
+			data, err := json.Marshal(convertTweet(tweetScraped.Tweet))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+
+		if !staleCSRF {
+			return
+		}
+
+		log.Println("Detected stale CSRF token mid-stream, refreshing ct0 and retrying search once")
+		if err := refreshScraperCSRF(scraper, pool.proxyAddr); err != nil {
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+type healthzAccount struct {
+	Nick    string `json:"nick"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request, store *accounts.Store, verify accounts.VerifyFunc) {
+	all, err := store.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	statuses := make([]healthzAccount, 0, len(all))
+	for _, acc := range all {
+		status := healthzAccount{Nick: acc.Nick, Healthy: true}
+		if err := verify(acc); err != nil {
+			status.Healthy = false
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
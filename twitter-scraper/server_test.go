@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"x-cookies/internal/accounts"
+)
+
+func newTestAccountsStore(t *testing.T) *accounts.Store {
+	t.Helper()
+	store, err := accounts.Open(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open in-memory store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestScraperPoolAcquireCachesScraper(t *testing.T) {
+	store := newTestAccountsStore(t)
+	if err := store.Upsert(accounts.Account{Nick: "alice", CookiesJSON: "[]", Status: accounts.StatusHealthy}); err != nil {
+		t.Fatalf("failed to seed account: %v", err)
+	}
+
+	pool := newScraperPool(store, "")
+
+	first, _, err := pool.acquire("alice")
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	second, _, err := pool.acquire("alice")
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if first != second {
+		t.Error("expected acquire to return the same cached scraper on repeated calls")
+	}
+}
+
+func TestScraperPoolAcquireRejectsUnhealthyAccount(t *testing.T) {
+	store := newTestAccountsStore(t)
+	if err := store.Upsert(accounts.Account{Nick: "bob", CookiesJSON: "[]", Status: accounts.StatusExpired}); err != nil {
+		t.Fatalf("failed to seed account: %v", err)
+	}
+
+	pool := newScraperPool(store, "")
+	if _, _, err := pool.acquire("bob"); err == nil {
+		t.Error("expected acquire to reject an unhealthy account")
+	}
+}
+
+func TestScraperPoolUpdateCookiesRefreshesCachedScraper(t *testing.T) {
+	store := newTestAccountsStore(t)
+	if err := store.Upsert(accounts.Account{Nick: "carol", CookiesJSON: "[]", Status: accounts.StatusHealthy}); err != nil {
+		t.Fatalf("failed to seed account: %v", err)
+	}
+
+	pool := newScraperPool(store, "")
+	scraper, _, err := pool.acquire("carol")
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	pool.updateCookies("carol", []*http.Cookie{{Name: "ct0", Value: "fresh-token"}})
+
+	found := false
+	for _, c := range scraper.GetCookies() {
+		if c.Name == "ct0" && c.Value == "fresh-token" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected updateCookies to push the refreshed ct0 into the already-cached scraper")
+	}
+}
+
+func TestScraperPoolUpdateCookiesIgnoresUncachedAccount(t *testing.T) {
+	store := newTestAccountsStore(t)
+	pool := newScraperPool(store, "")
+
+	// Should not panic even though "dave" was never acquired.
+	pool.updateCookies("dave", []*http.Cookie{{Name: "ct0", Value: "x"}})
+}
+
+func TestHandleSearchUnknownAccountReturns404(t *testing.T) {
+	store := newTestAccountsStore(t)
+	pool := newScraperPool(store, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(`{"query":"golang","account":"missing"}`))
+	rec := httptest.NewRecorder()
+
+	handleSearch(rec, req, pool)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown account, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandleSearchRejectsNonPOST(t *testing.T) {
+	store := newTestAccountsStore(t)
+	pool := newScraperPool(store, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/search", nil)
+	rec := httptest.NewRecorder()
+
+	handleSearch(rec, req, pool)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d for GET, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestHandleStreamUnknownAccountReturns404(t *testing.T) {
+	store := newTestAccountsStore(t)
+	pool := newScraperPool(store, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/stream?query=golang&account=missing", nil)
+	rec := httptest.NewRecorder()
+
+	handleStream(rec, req, pool)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown account, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandleHealthzReportsPerAccountStatus(t *testing.T) {
+	store := newTestAccountsStore(t)
+	if err := store.Upsert(accounts.Account{Nick: "healthy-acct", CookiesJSON: "[]", Status: accounts.StatusHealthy}); err != nil {
+		t.Fatalf("failed to seed account: %v", err)
+	}
+	if err := store.Upsert(accounts.Account{Nick: "stale-acct", CookiesJSON: "[]", Status: accounts.StatusHealthy}); err != nil {
+		t.Fatalf("failed to seed account: %v", err)
+	}
+
+	verify := func(acc accounts.Account) error {
+		if acc.Nick == "stale-acct" {
+			return errFakeExpired
+		}
+		return nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+
+	handleHealthz(rec, req, store, verify)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `"nick":"healthy-acct","healthy":true`) {
+		t.Errorf("expected healthy-acct to be reported healthy, got body: %s", body)
+	}
+	if !strings.Contains(body, `"nick":"stale-acct","healthy":false`) {
+		t.Errorf("expected stale-acct to be reported unhealthy, got body: %s", body)
+	}
+}
+
+var errFakeExpired = &fakeExpiredError{}
+
+type fakeExpiredError struct{}
+
+func (e *fakeExpiredError) Error() string { return "cookie expired" }
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OutputSink 接收逐条抓取到的推文，用于替代先攒成切片再一次性打印/序列化的旧流程
+type OutputSink interface {
+	Write(ctx context.Context, tweet *TweetResult) error
+	Close() error
+}
+
+// resolveOutputSink 根据 -output/-output-target 构造对应的 OutputSink
+func resolveOutputSink(kind, target string) (OutputSink, error) {
+	switch kind {
+	case "", "stdout":
+		return &stdoutSink{}, nil
+	case "ndjson":
+		return &ndjsonSink{w: os.Stdout}, nil
+	case "csv":
+		return &csvSink{w: csv.NewWriter(os.Stdout)}, nil
+	case "webhook":
+		if target == "" {
+			return nil, fmt.Errorf("-output-target is required for webhook output")
+		}
+		return newWebhookSink(target), nil
+	case "redis":
+		if target == "" {
+			return nil, fmt.Errorf("-output-target is required for redis output (redis://host:port?channel=<name>)")
+		}
+		return newRedisSink(target)
+	default:
+		return nil, fmt.Errorf("unknown output kind: %s", kind)
+	}
+}
+
+// stdoutSink 复用原有的人类可读输出格式，逐条打印
+type stdoutSink struct {
+	index int
+}
+
+func (s *stdoutSink) Write(_ context.Context, tweet *TweetResult) error {
+	s.index++
+	fmt.Printf("--- 推文 %d ---\n", s.index)
+	fmt.Printf("用户: @%s\n", tweet.Username)
+	fmt.Printf("时间: %s\n", tweet.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("内容: %s\n", tweet.Text)
+	fmt.Printf("互动: ❤️ %d | 🔄 %d | 💬 %d\n\n", tweet.Likes, tweet.Retweets, tweet.Replies)
+	return nil
+}
+
+func (s *stdoutSink) Close() error { return nil }
+
+// ndjsonSink 每行写入一条推文的 JSON，适合管道给下游程序消费
+type ndjsonSink struct {
+	w io.Writer
+}
+
+func (s *ndjsonSink) Write(_ context.Context, tweet *TweetResult) error {
+	data, err := json.Marshal(tweet)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tweet: %w", err)
+	}
+	_, err = fmt.Fprintf(s.w, "%s\n", data)
+	return err
+}
+
+func (s *ndjsonSink) Close() error { return nil }
+
+// csvSink 将推文写成 CSV 行，首次写入时输出表头
+type csvSink struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+var csvHeader = []string{"id", "username", "created_at", "text", "likes", "retweets", "replies", "is_retweet"}
+
+func (s *csvSink) Write(_ context.Context, tweet *TweetResult) error {
+	if !s.wroteHeader {
+		if err := s.w.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write csv header: %w", err)
+		}
+		s.wroteHeader = true
+	}
+
+	row := []string{
+		tweet.ID,
+		tweet.Username,
+		tweet.CreatedAt.Format(time.RFC3339),
+		tweet.Text,
+		strconv.Itoa(tweet.Likes),
+		strconv.Itoa(tweet.Retweets),
+		strconv.Itoa(tweet.Replies),
+		strconv.FormatBool(tweet.IsRetweet),
+	}
+	if err := s.w.Write(row); err != nil {
+		return fmt.Errorf("failed to write csv row: %w", err)
+	}
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *csvSink) Close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// webhookSink 将每条推文以 JSON POST 到配置的 URL，失败时指数退避重试
+type webhookSink struct {
+	url        string
+	httpClient *http.Client
+	maxRetries int
+}
+
+func newWebhookSink(url string) *webhookSink {
+	return &webhookSink{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		maxRetries: 3,
+	}
+}
+
+func (s *webhookSink) Write(ctx context.Context, tweet *TweetResult) error {
+	data, err := json.Marshal(tweet)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tweet: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<uint(attempt-1)) * time.Second
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to build webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			log.Printf("webhook post failed (attempt %d/%d): %v", attempt+1, s.maxRetries+1, err)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned status %s", resp.Status)
+		log.Printf("webhook post failed (attempt %d/%d): %v", attempt+1, s.maxRetries+1, lastErr)
+	}
+
+	return fmt.Errorf("webhook post failed after %d attempts: %w", s.maxRetries+1, lastErr)
+}
+
+func (s *webhookSink) Close() error { return nil }
+
+// redisSink 将每条推文发布到一个 Redis channel，供其他服务订阅消费
+type redisSink struct {
+	client  *redis.Client
+	channel string
+}
+
+func newRedisSink(target string) (*redisSink, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis target %q: %w", target, err)
+	}
+
+	channel := parsed.Query().Get("channel")
+	if channel == "" {
+		channel = "tweets"
+	}
+	// channel 是我们自己的参数，剥离后再交给 redis.ParseURL，
+	// 避免它对未知的查询参数报错
+	parsed.RawQuery = ""
+
+	opts, err := redis.ParseURL(parsed.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis target %q: %w", target, err)
+	}
+	if opts.Addr == "" {
+		return nil, fmt.Errorf("redis target must include a host:port, e.g. redis://host:port?channel=tweets")
+	}
+
+	return &redisSink{
+		client:  redis.NewClient(opts),
+		channel: channel,
+	}, nil
+}
+
+func (s *redisSink) Write(ctx context.Context, tweet *TweetResult) error {
+	data, err := json.Marshal(tweet)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tweet: %w", err)
+	}
+	if err := s.client.Publish(ctx, s.channel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish tweet to redis: %w", err)
+	}
+	return nil
+}
+
+func (s *redisSink) Close() error {
+	return s.client.Close()
+}
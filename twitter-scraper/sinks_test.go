@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVSinkWritesHeaderOnce(t *testing.T) {
+	var buf strings.Builder
+	sink := &csvSink{w: csv.NewWriter(&buf)}
+
+	tweet := &TweetResult{ID: "1", Username: "alice", CreatedAt: time.Unix(0, 0).UTC()}
+	if err := sink.Write(context.Background(), tweet); err != nil {
+		t.Fatalf("first write failed: %v", err)
+	}
+	if err := sink.Write(context.Background(), tweet); err != nil {
+		t.Fatalf("second write failed: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 1 header line + 2 rows, got %d lines: %q", len(lines), lines)
+	}
+	if lines[0] != strings.Join(csvHeader, ",") {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+}
+
+func TestWebhookSinkRetriesThenSucceeds(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink := newWebhookSink(server.URL)
+	if err := sink.Write(context.Background(), &TweetResult{ID: "1"}); err != nil {
+		t.Fatalf("expected webhook write to eventually succeed, got: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected exactly 2 attempts, got %d", attempts)
+	}
+}
+
+func TestNewRedisSinkParsesChannelFromTarget(t *testing.T) {
+	sink, err := newRedisSink("redis://127.0.0.1:6379?channel=my-tweets")
+	if err != nil {
+		t.Fatalf("newRedisSink failed: %v", err)
+	}
+	defer sink.Close()
+
+	if sink.channel != "my-tweets" {
+		t.Errorf("expected channel %q, got %q", "my-tweets", sink.channel)
+	}
+}
+
+func TestNewRedisSinkDefaultsChannel(t *testing.T) {
+	sink, err := newRedisSink("redis://127.0.0.1:6379")
+	if err != nil {
+		t.Fatalf("newRedisSink failed: %v", err)
+	}
+	defer sink.Close()
+
+	if sink.channel != "tweets" {
+		t.Errorf("expected default channel %q, got %q", "tweets", sink.channel)
+	}
+}